@@ -9,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
-	"github.com/go-openapi/spec"
 
 	"github.com/emicklei/go-restful/v3"
 )
@@ -18,11 +17,31 @@ const (
 	// KeyOpenAPITags is a Metadata key for a restful Route
 	KeyOpenAPITags = "openapi.tags"
 
+	// KeyOpenAPISecurity is a Metadata key for a restful Route, paralleling
+	// KeyOpenAPITags. Its value is a []map[string][]string describing the
+	// operation's security requirements.
+	KeyOpenAPISecurity = "openapi.security"
+
+	// KeyOpenAPIExamples is a Metadata key for a restful Route. Its value is
+	// an ExamplesByLocation scoping named examples to the operation's
+	// request body and/or its responses.
+	KeyOpenAPIExamples = "openapi.examples"
+
+	// KeyOpenAPIServers is a Metadata key for a restful Route. Its value is
+	// an openapi3.Servers that overrides Config.Servers for that single
+	// operation.
+	KeyOpenAPIServers = "openapi.servers"
+
 	// ExtensionPrefix is the only prefix accepted for VendorExtensible extension keys
 	ExtensionPrefix = "x-"
 
+	// exampleExtensionKey is the restful extension property recognized as a
+	// single OpenAPI 3 Example value for a parameter, request body, or response.
+	exampleExtensionKey = "x-example"
+
 	arrayType      = "array"
 	definitionRoot = "#/definitions/"
+	componentRoot  = "#/components/schemas/"
 )
 
 func buildPaths(ws *restful.WebService, cfg Config) openapi3.Paths {
@@ -39,6 +58,19 @@ func buildPaths(ws *restful.WebService, cfg Config) openapi3.Paths {
 	return p
 }
 
+// serversFromMetadata extracts an openapi3.Servers override from a
+// restful.Route Metadata lookup.
+func serversFromMetadata(value interface{}, ok bool) openapi3.Servers {
+	if !ok {
+		return nil
+	}
+	servers, ok := value.(openapi3.Servers)
+	if !ok {
+		return nil
+	}
+	return servers
+}
+
 // sanitizePath removes regex expressions from named path params,
 // since openapi only supports setting the pattern as a property named "pattern".
 // Expressions like "/api/v1/{name:[a-z]}/" are converted to "/api/v1/{name}/".
@@ -81,6 +113,13 @@ func buildOperation(ws *restful.WebService, r restful.Route, patterns map[string
 			}
 		}
 	}
+	o.Security = buildSecurity(r, cfg)
+	if r.Metadata != nil {
+		value, ok := r.Metadata[KeyOpenAPIServers]
+		if servers := serversFromMetadata(value, ok); servers != nil {
+			o.Servers = &servers
+		}
+	}
 
 	extractVendorExtensions(&o.ExtensionProps, r.ExtensionProperties)
 
@@ -89,28 +128,220 @@ func buildOperation(ws *restful.WebService, r restful.Route, patterns map[string
 		p := buildParameter(r, param, patterns[param.Data().Name], cfg)
 		o.Parameters = append(o.Parameters, p)
 	}
-	// route specific params
+	// route specific params; body params are modeled as the RequestBody, not a Parameter
 	for _, param := range r.ParameterDocs {
+		if param.Data().Kind == restful.BodyParameterKind {
+			o.RequestBody = buildRequestBody(r, param, cfg)
+			continue
+		}
 		p := buildParameter(r, param, patterns[param.Data().Name], cfg)
 		o.Parameters = append(o.Parameters, p)
 	}
-	o.Responses = new(spec.Responses)
-	props := &o.Responses.ResponsesProps
-	props.StatusCodeResponses = make(map[int]spec.Response, len(r.ResponseErrors))
+
+	o.Responses = make(openapi3.Responses, len(r.ResponseErrors))
 	for k, v := range r.ResponseErrors {
-		r := buildResponse(v, cfg)
-		props.StatusCodeResponses[k] = r
+		o.Responses[strconv.Itoa(k)] = buildResponse(v, r, cfg)
 	}
 	if r.DefaultResponse != nil {
-		rsp := buildResponse(*r.DefaultResponse, cfg)
-		o.Responses.Default = &rsp
+		o.Responses["default"] = buildResponse(*r.DefaultResponse, r, cfg)
 	}
-	if len(o.Responses.StatusCodeResponses) == 0 {
-		o.Responses.StatusCodeResponses[200] = spec.Response{ResponseProps: spec.ResponseProps{Description: http.StatusText(http.StatusOK)}}
+	if len(o.Responses) == 0 {
+		o.Responses[strconv.Itoa(http.StatusOK)] = &openapi3.ResponseRef{
+			Value: openapi3.NewResponse().WithDescription(http.StatusText(http.StatusOK)),
+		}
 	}
 	return o
 }
 
+// buildRequestBody builds an OpenAPI 3 RequestBody from a route's body
+// parameter, keyed by the route's Consumes MIME types.
+func buildRequestBody(r restful.Route, bodyParam *restful.Parameter, cfg Config) *openapi3.RequestBodyRef {
+	param := bodyParam.Data()
+
+	rb := openapi3.NewRequestBody()
+	rb.Description = param.Description
+	rb.Required = param.Required
+
+	mimeTypes := r.Consumes
+	if len(mimeTypes) == 0 {
+		mimeTypes = []string{"application/json"}
+	}
+
+	schema := bodySchemaRef(r.ReadSample, cfg)
+	example := exampleFor(param.ExtensionProperties, r.ReadSample, cfg)
+	named := namedExamples(r, false)
+	content := make(openapi3.Content, len(mimeTypes))
+	for _, mime := range mimeTypes {
+		mt := openapi3.NewMediaType().WithSchemaRef(schema)
+		if example != nil {
+			mt.Example = example
+		}
+		if named != nil {
+			mt.Examples = named
+		}
+		content[mime] = mt
+	}
+	rb.Content = content
+
+	extractVendorExtensions(&rb.ExtensionProps, param.ExtensionProperties)
+
+	return &openapi3.RequestBodyRef{Value: rb}
+}
+
+// bodySchemaRef builds the SchemaRef for a request or response body sample,
+// pointing at a component schema for structs and slices-of-structs, or
+// inlining the type for primitives.
+func bodySchemaRef(sample interface{}, cfg Config) *openapi3.SchemaRef {
+	if sample == nil {
+		return &openapi3.SchemaRef{Value: openapi3.NewSchema()}
+	}
+	st := reflect.TypeOf(sample)
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if disc, ok := discriminatorFor(st, cfg); ok {
+		return discriminatedSchemaRef(disc, cfg)
+	}
+	if st.Kind() == reflect.Array || st.Kind() == reflect.Slice {
+		items := componentOrPrimitiveSchemaRef(st.Elem(), cfg)
+		schema := openapi3.NewArraySchema()
+		schema.Items = items
+		return &openapi3.SchemaRef{Value: schema}
+	}
+	return componentOrPrimitiveSchemaRef(st, cfg)
+}
+
+// discriminatorFor finds the DiscriminatorSpec for the interface that st (a
+// concrete sample type) or a pointer to st implements. A sample may also be
+// the interface type itself, e.g. when passed as (*Animal)(nil) and
+// unwrapped by the caller, in which case it's looked up directly.
+func discriminatorFor(st reflect.Type, cfg Config) (DiscriminatorSpec, bool) {
+	if st.Kind() == reflect.Interface {
+		disc, ok := cfg.DiscriminatedTypes[st]
+		return disc, ok
+	}
+	for iface, disc := range cfg.DiscriminatedTypes {
+		if st.Implements(iface) || reflect.PtrTo(st).Implements(iface) {
+			return disc, true
+		}
+	}
+	return DiscriminatorSpec{}, false
+}
+
+// discriminatedSchemaRef builds a oneOf schema with a Discriminator for a Go
+// interface registered via Config.RegisterDiscriminator, registering each
+// implementer's own component schema so the oneOf refs aren't dangling.
+func discriminatedSchemaRef(disc DiscriminatorSpec, cfg Config) *openapi3.SchemaRef {
+	tags := make([]string, 0, len(disc.Mapping))
+	for tag := range disc.Mapping {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	oneOf := make([]*openapi3.SchemaRef, 0, len(tags))
+	mapping := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		implType := disc.Mapping[tag]
+		name := keyFrom(implType, cfg)
+		registerSchema(implType, name, cfg)
+		ref := componentRoot + name
+		oneOf = append(oneOf, &openapi3.SchemaRef{Ref: ref})
+		mapping[tag] = ref
+	}
+
+	schema := openapi3.NewSchema()
+	schema.OneOf = oneOf
+	schema.Discriminator = &openapi3.Discriminator{
+		PropertyName: disc.PropertyName,
+		Mapping:      mapping,
+	}
+	return &openapi3.SchemaRef{Value: schema}
+}
+
+// componentOrPrimitiveSchemaRef returns a $ref to #/components/schemas/<name>
+// for struct types, or an inline schema for primitives. Struct schemas are
+// generated (once) via registerSchema so that readOnly/writeOnly struct tags
+// make it into the component the $ref points at.
+func componentOrPrimitiveSchemaRef(st reflect.Type, cfg Config) *openapi3.SchemaRef {
+	name := keyFrom(st, cfg)
+	if isPrimitiveType(name) {
+		return &openapi3.SchemaRef{Value: openapi3.NewSchema().WithType(jsonSchemaType(name))}
+	}
+	registerSchema(st, name, cfg)
+	return &openapi3.SchemaRef{Ref: componentRoot + name}
+}
+
+// exampleFor resolves a single OpenAPI 3 example value, preferring an
+// explicit "x-example" vendor extension and falling back to
+// cfg.ExamplesForType keyed by sample's reflect.Type.
+func exampleFor(extensions restful.ExtensionProperties, sample interface{}, cfg Config) interface{} {
+	if extensions.Extensions != nil {
+		if v, ok := extensions.Extensions[exampleExtensionKey]; ok {
+			return v
+		}
+	}
+	if sample == nil || cfg.ExamplesForType == nil {
+		return nil
+	}
+	st := reflect.TypeOf(sample)
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	return cfg.ExamplesForType[st]
+}
+
+// ExamplesByLocation scopes the named examples set via KeyOpenAPIExamples so
+// that a request body's examples don't bleed onto the operation's responses,
+// and vice versa.
+type ExamplesByLocation struct {
+	// RequestBody is applied to the operation's request body content, if any.
+	RequestBody openapi3.Examples
+
+	// Responses is applied to every one of the operation's response bodies.
+	Responses openapi3.Examples
+}
+
+// namedExamples resolves the KeyOpenAPIExamples route metadata into the named
+// OpenAPI 3 examples for the request body (forResponse false) or the
+// responses (forResponse true).
+func namedExamples(r restful.Route, forResponse bool) openapi3.Examples {
+	if r.Metadata == nil {
+		return nil
+	}
+	value, ok := r.Metadata[KeyOpenAPIExamples]
+	if !ok {
+		return nil
+	}
+	byLocation, ok := value.(ExamplesByLocation)
+	if !ok {
+		return nil
+	}
+	if forResponse {
+		return byLocation.Responses
+	}
+	return byLocation.RequestBody
+}
+
+// buildSecurity resolves an operation's security requirements from its route
+// metadata, falling back to cfg.DefaultSecurity when the route defines none.
+func buildSecurity(r restful.Route, cfg Config) *openapi3.SecurityRequirements {
+	if r.Metadata != nil {
+		if sec, ok := r.Metadata[KeyOpenAPISecurity]; ok {
+			if reqs, ok := sec.([]map[string][]string); ok {
+				srs := make(openapi3.SecurityRequirements, 0, len(reqs))
+				for _, req := range reqs {
+					srs = append(srs, openapi3.SecurityRequirement(req))
+				}
+				return &srs
+			}
+		}
+	}
+	if cfg.DefaultSecurity != nil {
+		return &cfg.DefaultSecurity
+	}
+	return nil
+}
+
 // stringAutoType automatically picks the correct type from an ambiguously typed
 // string. Ex. numbers become int, true/false become bool, etc.
 func stringAutoType(ambiguous string) interface{} {
@@ -128,6 +359,9 @@ func stringAutoType(ambiguous string) interface{} {
 
 func extractVendorExtensions(extensible *openapi3.ExtensionProps, extensions restful.ExtensionProperties) {
 	if len(extensions.Extensions) > 0 {
+		if extensible.Extensions == nil {
+			extensible.Extensions = make(map[string]interface{}, len(extensions.Extensions))
+		}
 		for key, value := range extensions.Extensions {
 			if strings.HasPrefix(key, ExtensionPrefix) {
 				extensible.Extensions[key] = value
@@ -209,119 +443,81 @@ func buildParameter(r restful.Route, restfulParam *restful.Parameter, pattern st
 	} else if !param.AllowMultiple {
 		p.Schema.Value.Pattern = param.Pattern
 	}
-	st := reflect.TypeOf(r.ReadSample)
-	if param.Kind == restful.BodyParameterKind && r.ReadSample != nil && param.DataType == st.String() {
-		p.Schema = new(spec.Schema)
-		p.SimpleSchema = spec.SimpleSchema{}
-		if st.Kind() == reflect.Array || st.Kind() == reflect.Slice {
-			dataTypeName := keyFrom(st.Elem(), cfg)
-			p.Schema.Type = []string{arrayType}
-			p.Schema.Items = &spec.SchemaOrArray{
-				Schema: &spec.Schema{},
-			}
-			isPrimitive := isPrimitiveType(dataTypeName)
-			if isPrimitive {
-				mapped := jsonSchemaType(dataTypeName)
-				p.Schema.Items.Schema.Type = []string{mapped}
-			} else {
-				p.Schema.Items.Schema.Ref = spec.MustCreateRef(definitionRoot + dataTypeName)
-			}
-		} else {
-			dataTypeName := keyFrom(st, cfg)
-			p.Schema.Ref = spec.MustCreateRef(definitionRoot + dataTypeName)
-		}
 
-	} else {
-		if param.AllowMultiple {
-			p.Type = arrayType
-			p.Items = spec.NewItems()
-			p.Items.Type = param.DataType
-			p.CollectionFormat = param.CollectionFormat
-		} else {
-			p.Type = param.DataType
-		}
-		p.Default = stringAutoType(param.DefaultValue)
-		p.Format = param.DataFormat
+	if example := exampleFor(param.ExtensionProperties, nil, cfg); example != nil {
+		p.Example = example
 	}
 
-	extractVendorExtensions(&p.VendorExtensible, param.ExtensionProperties)
+	extractVendorExtensions(&p.ExtensionProps, param.ExtensionProperties)
 
-	return p
+	return &openapi3.ParameterRef{Value: p}
 }
 
-func buildResponse(e restful.ResponseError, cfg Config) (r spec.Response) {
-	r.Description = e.Message
+// buildResponse builds an OpenAPI 3 Response, with its body content keyed by
+// the route's Produces MIME types.
+func buildResponse(e restful.ResponseError, r restful.Route, cfg Config) *openapi3.ResponseRef {
+	resp := openapi3.NewResponse().WithDescription(e.Message)
+
 	if e.Model != nil {
-		st := reflect.TypeOf(e.Model)
-		if st.Kind() == reflect.Ptr {
-			// For pointer type, use element type as the key; otherwise we'll
-			// endup with '#/definitions/*Type' which violates openapi spec.
-			st = st.Elem()
+		mimeTypes := r.Produces
+		if len(mimeTypes) == 0 {
+			mimeTypes = []string{"application/json"}
 		}
-		r.Schema = new(spec.Schema)
-		if st.Kind() == reflect.Array || st.Kind() == reflect.Slice {
-			modelName := keyFrom(st.Elem(), cfg)
-			r.Schema.Type = []string{arrayType}
-			r.Schema.Items = &spec.SchemaOrArray{
-				Schema: &spec.Schema{},
-			}
-			isPrimitive := isPrimitiveType(modelName)
-			if isPrimitive {
-				mapped := jsonSchemaType(modelName)
-				r.Schema.Items.Schema.Type = []string{mapped}
-			} else {
-				r.Schema.Items.Schema.Ref = spec.MustCreateRef(definitionRoot + modelName)
+		schema := bodySchemaRef(e.Model, cfg)
+		example := exampleFor(e.ExtensionProperties, e.Model, cfg)
+		named := namedExamples(r, true)
+		content := make(openapi3.Content, len(mimeTypes))
+		for _, mime := range mimeTypes {
+			mt := openapi3.NewMediaType().WithSchemaRef(schema)
+			if example != nil {
+				mt.Example = example
 			}
-		} else {
-			modelName := keyFrom(st, cfg)
-			if isPrimitiveType(modelName) {
-				// If the response is a primitive type, then don't reference any definitions.
-				// Instead, set the schema's "type" to the model name.
-				r.Schema.AddType(modelName, "")
-			} else {
-				modelName := keyFrom(st, cfg)
-				r.Schema.Ref = spec.MustCreateRef(definitionRoot + modelName)
+			if named != nil {
+				mt.Examples = named
 			}
+			content[mime] = mt
 		}
+		resp.Content = content
 	}
 
 	if len(e.Headers) > 0 {
-		r.Headers = make(map[string]spec.Header, len(e.Headers))
+		resp.Headers = make(openapi3.Headers, len(e.Headers))
 		for k, v := range e.Headers {
-			r.Headers[k] = buildHeader(v)
+			resp.Headers[k] = buildHeader(v)
 		}
 	}
 
-	extractVendorExtensions(&r.VendorExtensible, e.ExtensionProperties)
-	return r
+	extractVendorExtensions(&resp.ExtensionProps, e.ExtensionProperties)
+	return &openapi3.ResponseRef{Value: resp}
 }
 
-// buildHeader builds a specification header structure from restful.Header
-func buildHeader(header restful.Header) spec.Header {
-	responseHeader := spec.Header{}
-	responseHeader.Type = header.Type
-	responseHeader.Description = header.Description
+// buildHeader builds an OpenAPI 3 header from a restful.Header.
+func buildHeader(header restful.Header) *openapi3.HeaderRef {
+	h := &openapi3.Header{Parameter: openapi3.Parameter{
+		Description: header.Description,
+		Schema:      &openapi3.SchemaRef{Value: openapi3.NewSchema()},
+	}}
+	h.Schema.Value.Type = header.Type
 
 	// If type is "array" items field is required
 	if header.Type == arrayType {
-		responseHeader.Items = buildHeadersItems(header.Items)
+		h.Schema.Value.Items = buildHeadersItems(header.Items)
 	}
 
-	return responseHeader
+	return &openapi3.HeaderRef{Value: h}
 }
 
-// buildHeadersItems builds
-func buildHeadersItems(items *restful.Items) *spec.Items {
-	responseItems := spec.NewItems()
+// buildHeadersItems builds the item schema for an array-typed header.
+func buildHeadersItems(items *restful.Items) *openapi3.SchemaRef {
+	responseItems := openapi3.NewSchema()
 	responseItems.Format = items.Format
 	responseItems.Type = items.Type
 	responseItems.Default = items.Default
-	responseItems.CollectionFormat = items.CollectionFormat
 	if items.Items != nil {
 		responseItems.Items = buildHeadersItems(items.Items)
 	}
 
-	return responseItems
+	return &openapi3.SchemaRef{Value: responseItems}
 }
 
 // stripTags takes a snippet of HTML and returns only the text content.
@@ -0,0 +1,88 @@
+package restfulspec
+
+import (
+	"reflect"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Config holds the options that control how BuildOpenAPI renders a
+// restful.Container into an OpenAPI 3 document.
+type Config struct {
+	// SecuritySchemes are copied into the generated document's
+	// Components.SecuritySchemes, keyed by scheme name.
+	SecuritySchemes map[string]*openapi3.SecuritySchemeRef
+
+	// DefaultSecurity is applied to a route whose metadata does not define
+	// its own security requirements via KeyOpenAPISecurity.
+	DefaultSecurity openapi3.SecurityRequirements
+
+	// ExamplesForType supplies a default Example value for a request body or
+	// response whose sample is of the given type, when no "x-example" vendor
+	// extension is set on the route directly. Parameters have no sample type,
+	// so their Example comes only from an "x-example" vendor extension.
+	ExamplesForType map[reflect.Type]interface{}
+
+	// DiscriminatedTypes maps a Go interface type to the DiscriminatorSpec
+	// describing how it renders as a oneOf schema. Populate it via
+	// RegisterDiscriminator rather than directly.
+	DiscriminatedTypes map[reflect.Type]DiscriminatorSpec
+
+	// Servers populates the root document's Servers, replacing the openapi2
+	// basePath/host/schemes model. A Route can override these for its own
+	// operation via KeyOpenAPIServers metadata.
+	Servers openapi3.Servers
+
+	// Schemas accumulates the component schemas generated for non-primitive
+	// request/response bodies, keyed by component name. BuildOpenAPI
+	// initializes this when nil and uses it as Components.Schemas, so the
+	// schemas are generated whether or not it is set.
+	Schemas openapi3.Schemas
+}
+
+// DiscriminatorSpec describes how a Go interface renders as an OpenAPI 3
+// polymorphic schema: a oneOf listing each concrete implementer plus a
+// Discriminator mapping property values to those implementers.
+type DiscriminatorSpec struct {
+	// PropertyName is the JSON field used to discriminate between the
+	// concrete implementers.
+	PropertyName string
+
+	// Mapping maps each discriminator property value to the concrete Go
+	// type it selects. The component schema name and the schema itself are
+	// resolved (and generated) lazily, when the oneOf is built.
+	Mapping map[string]reflect.Type
+}
+
+// RegisterDiscriminator records iface (passed as a nil pointer, e.g.
+// (*Animal)(nil)) as a polymorphic type rendered as a oneOf schema
+// discriminated by propertyName. impls maps each discriminator value to a
+// sample of the concrete type it selects, e.g. map[string]interface{}{"dog":
+// Dog{}, "cat": Cat{}}.
+func (c *Config) RegisterDiscriminator(iface interface{}, propertyName string, impls map[string]interface{}) {
+	if c.DiscriminatedTypes == nil {
+		c.DiscriminatedTypes = map[reflect.Type]DiscriminatorSpec{}
+	}
+	mapping := make(map[string]reflect.Type, len(impls))
+	for tag, impl := range impls {
+		implType := reflect.TypeOf(impl)
+		if implType.Kind() == reflect.Ptr {
+			implType = implType.Elem()
+		}
+		mapping[tag] = implType
+	}
+	c.DiscriminatedTypes[interfaceType(iface)] = DiscriminatorSpec{
+		PropertyName: propertyName,
+		Mapping:      mapping,
+	}
+}
+
+// interfaceType unwraps the nil-pointer convention ((*Iface)(nil)) used to
+// pass an interface type as a value.
+func interfaceType(iface interface{}) reflect.Type {
+	t := reflect.TypeOf(iface)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
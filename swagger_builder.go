@@ -0,0 +1,36 @@
+package restfulspec
+
+import (
+	"github.com/emicklei/go-restful/v3"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// BuildOpenAPI builds an OpenAPI 3 document describing every WebService
+// registered on the container.
+func BuildOpenAPI(container *restful.Container, cfg Config) *openapi3.T {
+	if cfg.Schemas == nil {
+		cfg.Schemas = openapi3.Schemas{}
+	}
+	swagger := &openapi3.T{
+		OpenAPI: "3.0.0",
+		Paths:   openapi3.Paths{},
+		Servers: cfg.Servers,
+		Components: openapi3.Components{
+			SecuritySchemes: cfg.SecuritySchemes,
+			Schemas:         cfg.Schemas,
+		},
+	}
+	for _, ws := range container.RegisteredWebServices() {
+		for path, item := range buildPaths(ws, cfg) {
+			existingPathItem, ok := swagger.Paths[path]
+			if !ok {
+				swagger.Paths[path] = item
+				continue
+			}
+			for method, op := range item.Operations() {
+				existingPathItem.SetOperation(method, op)
+			}
+		}
+	}
+	return swagger
+}
@@ -0,0 +1,48 @@
+package restfulspec
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+)
+
+// openapiTag is the struct tag recognized for schema-level annotations that
+// have no equivalent restful.Parameter field, such as readOnly/writeOnly.
+const openapiTag = "openapi"
+
+// customizeReadWriteOnly is an openapi3gen.SchemaCustomizerFunc passed to
+// registerSchema's NewSchemaRefForValue call, so that a struct field tagged
+// `openapi:"readOnly"` or `openapi:"writeOnly"` sets the corresponding flag
+// on the generated property schema.
+func customizeReadWriteOnly(_ string, _ reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	for _, flag := range strings.Split(tag.Get(openapiTag), ",") {
+		switch strings.TrimSpace(flag) {
+		case "readOnly":
+			schema.ReadOnly = true
+		case "writeOnly":
+			schema.WriteOnly = true
+		}
+	}
+	return nil
+}
+
+// registerSchema generates st's full OpenAPI 3 schema via openapi3gen,
+// applying customizeReadWriteOnly, and stashes it on cfg.Schemas under name
+// so BuildOpenAPI can copy it into Components.Schemas. When cfg.Schemas is
+// nil (BuildOpenAPI always initializes it) nothing is generated and the
+// caller's $ref is left without a schema behind it.
+func registerSchema(st reflect.Type, name string, cfg Config) {
+	if cfg.Schemas == nil {
+		return
+	}
+	if _, ok := cfg.Schemas[name]; ok {
+		return
+	}
+	ref, err := openapi3gen.NewSchemaRefForValue(reflect.New(st).Elem().Interface(), cfg.Schemas, openapi3gen.SchemaCustomizer(customizeReadWriteOnly))
+	if err != nil {
+		return
+	}
+	cfg.Schemas[name] = ref
+}
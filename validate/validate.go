@@ -0,0 +1,122 @@
+// Package validate provides an optional restful.FilterFunction that
+// validates incoming requests, and optionally outgoing responses, against an
+// OpenAPI 3 document built by restfulspec.
+//
+// Schema-level readOnly/writeOnly annotations are honored for free: request
+// validation rejects a body that sets a readOnly property, and (when
+// ValidateResponses is enabled) response validation flags a body that sets a
+// writeOnly property, since openapi3filter checks both against the schema.
+package validate
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// Options controls the behavior of the Filter returned by NewFilter.
+type Options struct {
+	// ValidateResponses, when true, also validates the response body written
+	// by downstream handlers against the matched operation's response schema.
+	ValidateResponses bool
+
+	// MultiError, when true, aggregates every schema violation found instead
+	// of failing on the first one. Clients get the full list of problems
+	// with their request in one round trip rather than fixing issues one at
+	// a time.
+	MultiError bool
+
+	// ErrorEncoder writes a validation failure to the client. Defaults to
+	// writing err.Error() as a plain-text body with the given status.
+	ErrorEncoder func(resp *restful.Response, status int, err error)
+}
+
+// NewFilter returns a restful.FilterFunction that validates requests (and,
+// if opts.ValidateResponses is set, responses) against spec. Requests that
+// don't match any operation in spec are passed through unvalidated.
+func NewFilter(spec *openapi3.T, opts Options) (restful.FilterFunction, error) {
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ErrorEncoder == nil {
+		opts.ErrorEncoder = defaultErrorEncoder
+	}
+
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		route, pathParams, err := router.FindRoute(req.Request)
+		if err != nil {
+			chain.ProcessFilter(req, resp)
+			return
+		}
+
+		ctx := req.Request.Context()
+		input := &openapi3filter.RequestValidationInput{
+			Request:    req.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &openapi3filter.Options{MultiError: opts.MultiError},
+		}
+		if err := openapi3filter.ValidateRequest(ctx, input); err != nil {
+			opts.ErrorEncoder(resp, http.StatusBadRequest, err)
+			return
+		}
+
+		if !opts.ValidateResponses {
+			chain.ProcessFilter(req, resp)
+			return
+		}
+
+		realWriter := resp.ResponseWriter
+		rec := newResponseRecorder(realWriter)
+		resp.ResponseWriter = rec
+		chain.ProcessFilter(req, resp)
+
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rec.status,
+			Header:                 rec.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+			Options:                &openapi3filter.Options{MultiError: opts.MultiError},
+		}
+		if err := openapi3filter.ValidateResponse(ctx, responseInput); err != nil {
+			resp.ResponseWriter = realWriter
+			opts.ErrorEncoder(resp, http.StatusBadGateway, err)
+			return
+		}
+
+		realWriter.WriteHeader(rec.status)
+		_, _ = realWriter.Write(rec.body.Bytes())
+	}, nil
+}
+
+func defaultErrorEncoder(resp *restful.Response, status int, err error) {
+	resp.WriteErrorString(status, err.Error())
+}
+
+// responseRecorder buffers a handler's status and body so they can be
+// validated before anything reaches the real client. Neither is forwarded to
+// the wrapped ResponseWriter until the caller flushes it explicitly. Headers
+// are not buffered: Header() is the wrapped ResponseWriter's own header map.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
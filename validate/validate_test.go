@@ -0,0 +1,119 @@
+package validate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// testSpec returns a minimal OpenAPI 3 document describing a single
+// POST /items operation: the request body requires a "name" string, and the
+// 200 response requires an "id" string.
+func testSpec() *openapi3.T {
+	requestSchema := openapi3.NewObjectSchema().
+		WithProperty("name", openapi3.NewStringSchema())
+	requestSchema.Required = []string{"name"}
+
+	responseSchema := openapi3.NewObjectSchema().
+		WithProperty("id", openapi3.NewStringSchema())
+	responseSchema.Required = []string{"id"}
+
+	op := openapi3.NewOperation()
+	op.OperationID = "createItem"
+	op.RequestBody = &openapi3.RequestBodyRef{
+		Value: openapi3.NewRequestBody().
+			WithRequired(true).
+			WithJSONSchema(requestSchema),
+	}
+	op.Responses = openapi3.NewResponses()
+	op.Responses["200"] = &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("OK").
+			WithJSONSchema(responseSchema),
+	}
+
+	doc := &openapi3.T{
+		OpenAPI: "3.0.0",
+		Info:    &openapi3.Info{Title: "test", Version: "1.0"},
+		Paths:   openapi3.Paths{},
+	}
+	doc.Paths["/items"] = &openapi3.PathItem{Post: op}
+	return doc
+}
+
+// newTestContainer wires filter in front of a WebService whose handler
+// writes body as the response, so tests can control the downstream
+// response independently of the request.
+func newTestContainer(t *testing.T, filter restful.FilterFunction, body string, status int) *restful.Container {
+	t.Helper()
+
+	container := restful.NewContainer()
+	ws := new(restful.WebService).Path("/items").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	ws.Filter(filter)
+	ws.Route(ws.POST("").To(func(req *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(status)
+		_, _ = resp.Write([]byte(body))
+	}))
+	container.Add(ws)
+	return container
+}
+
+func doPost(container *restful.Container, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", restful.MIME_JSON)
+	rec := httptest.NewRecorder()
+	container.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestFilter_RejectsInvalidRequest(t *testing.T) {
+	filter, err := NewFilter(testSpec(), Options{})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	container := newTestContainer(t, filter, `{"id":"1"}`, http.StatusOK)
+
+	rec := doPost(container, `{}`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestFilter_RejectsInvalidResponse(t *testing.T) {
+	filter, err := NewFilter(testSpec(), Options{ValidateResponses: true})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	container := newTestContainer(t, filter, `{}`, http.StatusOK)
+
+	rec := doPost(container, `{"name":"widget"}`)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if rec.Body.Len() == 0 {
+		t.Fatal("expected an error body describing the validation failure")
+	}
+}
+
+func TestFilter_PassesValidRequestAndResponse(t *testing.T) {
+	filter, err := NewFilter(testSpec(), Options{ValidateResponses: true})
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	container := newTestContainer(t, filter, `{"id":"1"}`, http.StatusCreated)
+
+	rec := doPost(container, `{"name":"widget"}`)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if got := rec.Body.String(); got != `{"id":"1"}` {
+		t.Fatalf("body = %q, want %q", got, `{"id":"1"}`)
+	}
+}